@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+type contextKey string
+
+const authedUserContextKey contextKey = "authedUser"
+
+func withAuthedUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, authedUserContextKey, user)
+}
+
+// authedUserFromContext returns the GitHub-verified user for this request,
+// if the auth middleware ran and succeeded.
+func authedUserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(authedUserContextKey).(User)
+	return user, ok
+}
+
+// githubUserURL is the GitHub API endpoint used to verify a token. It's a
+// var so tests can point it at a stub server.
+var githubUserURL = "https://api.github.com/user"
+
+// tokenCacheTTL controls how long a verified token is trusted before we
+// re-check it against GitHub.
+const tokenCacheTTL = 5 * time.Minute
+
+type tokenCacheEntry struct {
+	user      User
+	expiresAt time.Time
+}
+
+// tokenCache is a short-lived in-memory cache of verified tokens, keyed by
+// the sha256 hash of the token so we never hold the raw token in memory
+// longer than the request that presented it.
+type tokenCache struct {
+	mu      sync.Mutex
+	entries map[string]tokenCacheEntry
+}
+
+func newTokenCache() *tokenCache {
+	return &tokenCache{entries: make(map[string]tokenCacheEntry)}
+}
+
+func (c *tokenCache) get(key string) (User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return User{}, false
+	}
+	return entry.user, true
+}
+
+func (c *tokenCache) set(key string, user User) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = tokenCacheEntry{user: user, expiresAt: time.Now().Add(tokenCacheTTL)}
+}
+
+var globalTokenCache = newTokenCache()
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// authDisabled reports whether the GitHub token check should be skipped,
+// e.g. for local dev. Mirrors the other boolean env flags in this package.
+func authDisabled() bool {
+	return os.Getenv("AUTH_DISABLED") == "true"
+}
+
+// verifyGitHubToken checks the token against GitHub's /user endpoint,
+// consulting the in-memory cache first so we don't hammer the API on
+// every request.
+func verifyGitHubToken(r *http.Request, token string) (User, error) {
+	key := hashToken(token)
+	if user, ok := globalTokenCache.get(key); ok {
+		return user, nil
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, githubUserURL, nil)
+	if err != nil {
+		return User{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return User{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return User{}, fmt.Errorf("github token verification failed: status %d", resp.StatusCode)
+	}
+
+	var ghUser struct {
+		Login string `json:"login"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ghUser); err != nil {
+		return User{}, err
+	}
+
+	user := User{Login: ghUser.Login}
+	globalTokenCache.set(key, user)
+	return user, nil
+}
+
+// writeAuthError writes a 401 response in the SSE error frame shape Copilot
+// expects, so the client renders it instead of treating the connection as
+// a transport failure.
+func writeAuthError(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusUnauthorized)
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"error": map[string]string{
+			"message": message,
+			"type":    "invalid_request_error",
+		},
+	})
+	fmt.Fprintf(w, "data: %s\n\n", payload)
+}
+
+// withGitHubAuth verifies the X-GitHub-Token header Copilot forwards on
+// every extension call and, on success, overrides req.User with the
+// GitHub-verified identity before calling next. Set AUTH_DISABLED=true to
+// bypass this for local dev.
+func withGitHubAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if authDisabled() {
+			next(w, r)
+			return
+		}
+
+		token := r.Header.Get("X-GitHub-Token")
+		if token == "" {
+			setIntent(r.Context(), "auth_missing_token")
+			writeAuthError(w, "missing X-GitHub-Token header")
+			return
+		}
+
+		user, err := verifyGitHubToken(r, token)
+		if err != nil {
+			setIntent(r.Context(), "auth_invalid_token")
+			writeAuthError(w, "invalid GitHub token")
+			return
+		}
+
+		r = r.WithContext(withAuthedUser(r.Context(), user))
+		next(w, r)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func withStubGitHub(t *testing.T, login string, status int) func() {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"login":"` + login + `"}`))
+	}))
+
+	original := githubUserURL
+	githubUserURL = server.URL
+
+	return func() {
+		server.Close()
+		githubUserURL = original
+	}
+}
+
+func TestWithGitHubAuth_MissingToken(t *testing.T) {
+	os.Unsetenv("AUTH_DISABLED")
+
+	handlerCalled := false
+	handler := withGitHubAuth(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalled = true
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if handlerCalled {
+		t.Fatal("expected next handler not to be called without a token")
+	}
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", rec.Code)
+	}
+	if !strings.HasPrefix(rec.Body.String(), "data: ") {
+		t.Fatalf("expected SSE error frame, got %q", rec.Body.String())
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatal("expected 401 responses to still carry the CORS header so browser clients can read the SSE error frame")
+	}
+}
+
+func TestWithGitHubAuth_InvalidToken(t *testing.T) {
+	os.Unsetenv("AUTH_DISABLED")
+	cleanup := withStubGitHub(t, "", http.StatusUnauthorized)
+	defer cleanup()
+	globalTokenCache = newTokenCache()
+
+	handler := withGitHubAuth(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("next handler should not be called for an invalid token")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("X-GitHub-Token", "bad-token")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", rec.Code)
+	}
+}
+
+func TestWithGitHubAuth_ValidToken(t *testing.T) {
+	os.Unsetenv("AUTH_DISABLED")
+	cleanup := withStubGitHub(t, "octocat", http.StatusOK)
+	defer cleanup()
+	globalTokenCache = newTokenCache()
+
+	var gotUser User
+	handler := withGitHubAuth(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, _ = authedUserFromContext(r.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("X-GitHub-Token", "good-token")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if gotUser.Login != "octocat" {
+		t.Fatalf("expected authed user login %q, got %q", "octocat", gotUser.Login)
+	}
+}
+
+func TestWithGitHubAuth_Disabled(t *testing.T) {
+	os.Setenv("AUTH_DISABLED", "true")
+	defer os.Unsetenv("AUTH_DISABLED")
+
+	handlerCalled := false
+	handler := withGitHubAuth(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalled = true
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if !handlerCalled {
+		t.Fatal("expected next handler to be called when AUTH_DISABLED=true")
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// logger is the structured logger used for all per-request logging. JSON
+// output so it's easy to ship to a log aggregator.
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+const (
+	requestIDContextKey      contextKey = "requestID"
+	requestMetricsContextKey contextKey = "requestMetrics"
+)
+
+// requestMetrics accumulates the fields a handler discovers while serving
+// a request (e.g. which intent it classified the message as) so the
+// instrumenting middleware can log and count them once the handler
+// returns.
+type requestMetrics struct {
+	intent       string
+	ttfbRecorded bool
+}
+
+func requestMetricsFromContext(ctx context.Context) *requestMetrics {
+	m, _ := ctx.Value(requestMetricsContextKey).(*requestMetrics)
+	return m
+}
+
+// setIntent records the classified intent for the current request, if the
+// context is carrying a requestMetrics (i.e. instrumentChat is in the
+// handler chain). Safe to call when it isn't.
+func setIntent(ctx context.Context, intent string) {
+	if rm := requestMetricsFromContext(ctx); rm != nil {
+		rm.intent = intent
+	}
+}
+
+func generateRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return hex.EncodeToString([]byte(time.Now().String()))
+	}
+	return hex.EncodeToString(buf)
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+func loginFromContext(ctx context.Context) string {
+	if user, ok := authedUserFromContext(ctx); ok {
+		return user.Login
+	}
+	return ""
+}
+
+// withRequestID assigns an X-Request-ID if the caller didn't send one,
+// echoes it back on the response, and makes it available to handlers via
+// the request context.
+func withRequestID(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = generateRequestID()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+
+		ctx := context.WithValue(r.Context(), requestIDContextKey, requestID)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// instrumentHandler wraps a non-streaming handler with a request duration
+// observation and a structured completion log.
+func instrumentHandler(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next(w, r)
+		duration := time.Since(start)
+
+		requestDuration.WithLabelValues(name).Observe(duration.Seconds())
+		logger.Info("request completed",
+			"handler", name,
+			"request_id", requestIDFromContext(r.Context()),
+			"duration_ms", duration.Milliseconds(),
+		)
+	}
+}
+
+// instrumentChat wraps chatHandler with the streaming-specific metrics
+// (in-flight gauge, intent counter) on top of what instrumentHandler does
+// for every other endpoint.
+func instrumentChat(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := context.WithValue(r.Context(), requestStartContextKey, start)
+		ctx = context.WithValue(ctx, requestMetricsContextKey, &requestMetrics{})
+		r = r.WithContext(ctx)
+
+		inFlightStreams.Inc()
+		defer inFlightStreams.Dec()
+
+		next(w, r)
+
+		duration := time.Since(start)
+		intent := "unknown"
+		if m := requestMetricsFromContext(r.Context()); m != nil && m.intent != "" {
+			intent = m.intent
+		}
+
+		requestsTotal.WithLabelValues(intent).Inc()
+		requestDuration.WithLabelValues("chat").Observe(duration.Seconds())
+		logger.Info("chat request completed",
+			"request_id", requestIDFromContext(r.Context()),
+			"github_login", loginFromContext(r.Context()),
+			"intent", intent,
+			"duration_ms", duration.Milliseconds(),
+		)
+	}
+}
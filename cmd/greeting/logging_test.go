@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithRequestID_GeneratesWhenMissing(t *testing.T) {
+	var sawID string
+	handler := withRequestID(func(w http.ResponseWriter, r *http.Request) {
+		sawID = requestIDFromContext(r.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if sawID == "" {
+		t.Fatal("expected a generated request id to reach the handler")
+	}
+	if rec.Header().Get("X-Request-ID") != sawID {
+		t.Fatalf("expected X-Request-ID response header to match context value %q, got %q", sawID, rec.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestWithRequestID_PreservesIncoming(t *testing.T) {
+	var sawID string
+	handler := withRequestID(func(w http.ResponseWriter, r *http.Request) {
+		sawID = requestIDFromContext(r.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("X-Request-ID", "my-trace-id")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if sawID != "my-trace-id" {
+		t.Fatalf("expected incoming request id to be preserved, got %q", sawID)
+	}
+}
@@ -2,22 +2,25 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Request structures for Copilot extension
 type CopilotRequest struct {
 	Messages []Message `json:"messages"`
 	User     User      `json:"user"`
+	Tools    []Tool    `json:"tools,omitempty"`
 }
 
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string `json:"role"`
+	Content    string `json:"content"`
+	ToolCallID string `json:"tool_call_id,omitempty"`
 }
 
 type User struct {
@@ -27,6 +30,7 @@ type User struct {
 // Response structures
 type CopilotResponse struct {
 	Choices []Choice `json:"choices"`
+	Tools   []Tool   `json:"tools,omitempty"`
 }
 
 type Choice struct {
@@ -36,14 +40,20 @@ type Choice struct {
 }
 
 type Delta struct {
-	Role    string `json:"role,omitempty"`
-	Content string `json:"content,omitempty"`
+	Role       string     `json:"role,omitempty"`
+	Content    string     `json:"content,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
 }
 
 type GreetingResponse struct {
 	Message string `json:"message"`
 }
 
+// tableCacheProvider backs the table-cache lookups in chatHandler. It's
+// selected at startup from CACHE_PROVIDER (see newTableCacheProviderFromEnv).
+var tableCacheProvider TableCacheProvider
+
 // GET endpoint for greeting
 func greetingHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -82,17 +92,10 @@ func chatHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	log.Printf("Received message: %s", userMessage)
-
-	// Determine response type based on message content
-	var responseContent string
-	if isGreetingMessage(userMessage) {
-		responseContent = getGreetingMessage()
-	} else if isTableCacheQuery(userMessage) {
-		tableName := extractTableName(userMessage)
-		responseContent = generateTableCacheResponse(tableName)
-	} else {
-		responseContent = getHelpMessage()
+	// The auth middleware is the source of truth for who's calling us; it
+	// overrides whatever the client claimed in the request body.
+	if authedUser, ok := authedUserFromContext(r.Context()); ok {
+		req.User = authedUser
 	}
 
 	// Set headers for Server-Sent Events (SSE) format that Copilot expects
@@ -101,23 +104,40 @@ func chatHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	// Send the response in Copilot's expected format
-	response := CopilotResponse{
-		Choices: []Choice{
-			{
-				Index: 0,
-				Delta: Delta{
-					Role:    "assistant",
-					Content: responseContent,
-				},
-				Finish: "stop",
-			},
-		},
+	// Determine response type based on message content
+	if isGreetingMessage(userMessage) {
+		setIntent(r.Context(), "greeting")
+		writeSSEStream(w, r, getGreetingMessage())
+		return
+	}
+
+	if isTableCacheQuery(userMessage) {
+		tableName := extractTableName(userMessage)
+
+		// Prefer a proper tool call when Copilot advertised support for
+		// one; fall back to the heuristic text parser otherwise, since
+		// not every model drives function-calling turns.
+		if clientSupportsTools(req) {
+			setIntent(r.Context(), "table_cache_tool_call")
+			writeToolCallStream(w, r, tableCacheProvider, tableName)
+			return
+		}
+
+		setIntent(r.Context(), "table_cache_heuristic")
+		caches, err := tableCacheProvider.Lookup(r.Context(), tableName)
+		if err != nil {
+			logger.Warn("table cache lookup failed",
+				"request_id", requestIDFromContext(r.Context()),
+				"table_name", tableName,
+				"error", err.Error(),
+			)
+		}
+		writeSSEStream(w, r, formatTableCacheResponse(tableName, caches, err))
+		return
 	}
 
-	// Convert to JSON and send
-	jsonData, _ := json.Marshal(response)
-	fmt.Fprintf(w, "data: %s\n\n", jsonData)
+	setIntent(r.Context(), "help")
+	writeSSEStream(w, r, getHelpMessage())
 }
 
 // Check if message is a greeting
@@ -199,33 +219,6 @@ func extractTableName(message string) string {
 	return "UNKNOWN"
 }
 
-// Generate table cache response
-func generateTableCacheResponse(tableName string) string {
-	switch tableName {
-	case "TBCD":
-		return `Table Cache Information for TBCD:
-- MENUCACHE
-- APICACHE
-- TRANSCACHE`
-	
-	case "USERS":
-		return `Table Cache Information for USERS:
-- USERCACHE
-- REGISTRYCACHE`
-	
-	case "ORDERS":
-		return `Table Cache Information for ORDERS:
-- ORDERCACHE`
-	
-	default:
-		return fmt.Sprintf(`Table Cache Information for %s:
-- Status: Table not found in cache system
-- Suggestion: Please check if the table name is correct
-- Available cached tables: TBCD, USERS, ORDERS
-- Contact admin if you need to add this table to cache monitoring`, tableName)
-	}
-}
-
 // Health check endpoint
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -242,11 +235,18 @@ func main() {
 		port = "8080"
 	}
 
+	provider, err := newTableCacheProviderFromEnv()
+	if err != nil {
+		log.Fatal("Failed to initialize table cache provider:", err)
+	}
+	tableCacheProvider = newInstrumentedTableCacheProvider(provider)
+
 	// Setup routes
-	http.HandleFunc("/", greetingHandler)
-	http.HandleFunc("/greeting", greetingHandler)
-	http.HandleFunc("/v1/chat/completions", chatHandler)  // Copilot extension endpoint
-	http.HandleFunc("/health", healthHandler)
+	http.HandleFunc("/", withRequestID(instrumentHandler("greeting", greetingHandler)))
+	http.HandleFunc("/greeting", withRequestID(instrumentHandler("greeting", greetingHandler)))
+	http.HandleFunc("/v1/chat/completions", withRequestID(instrumentChat(withGitHubAuth(chatHandler)))) // Copilot extension endpoint
+	http.HandleFunc("/health", withRequestID(instrumentHandler("health", healthHandler)))
+	http.Handle("/metrics", promhttp.Handler())
 
 	// CORS middleware for all requests
 	http.HandleFunc("/cors-proxy", func(w http.ResponseWriter, r *http.Request) {
@@ -267,7 +267,8 @@ func main() {
 	log.Printf("  GET  /greeting - Get greeting message")
 	log.Printf("  POST /v1/chat/completions - Chat completions for Copilot")
 	log.Printf("  GET  /health - Health check")
-	
+	log.Printf("  GET  /metrics - Prometheus metrics")
+
 	if err := http.ListenAndServe(":"+port, nil); err != nil {
 		log.Fatal("Server failed to start:", err)
 	}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const requestStartContextKey contextKey = "requestStart"
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "greet_copilot_ext_requests_total",
+		Help: "Total chat requests, labeled by classified intent.",
+	}, []string{"intent"})
+
+	providerErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "greet_copilot_ext_provider_errors_total",
+		Help: "Total table cache provider errors, labeled by operation.",
+	}, []string{"operation"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "greet_copilot_ext_request_duration_seconds",
+		Help:    "End-to-end handler latency.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"handler"})
+
+	timeToFirstFrame = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "greet_copilot_ext_time_to_first_frame_seconds",
+		Help:    "Time from request start to the first SSE frame being flushed.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	providerLookupDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "greet_copilot_ext_provider_lookup_duration_seconds",
+		Help:    "Table cache provider call latency, labeled by operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	inFlightStreams = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "greet_copilot_ext_inflight_streams",
+		Help: "Number of chat responses currently streaming.",
+	})
+)
+
+// recordTimeToFirstFrame observes how long it took to flush the first SSE
+// frame of a chat response, relative to when instrumentChat started timing
+// the request. Idempotent per request: a tool-calling response flushes a
+// tool_calls frame and then streams a follow-up assistant message, and
+// only the first of those frames should count as "first".
+func recordTimeToFirstFrame(ctx context.Context) {
+	if rm := requestMetricsFromContext(ctx); rm != nil {
+		if rm.ttfbRecorded {
+			return
+		}
+		rm.ttfbRecorded = true
+	}
+
+	start, ok := ctx.Value(requestStartContextKey).(time.Time)
+	if !ok {
+		return
+	}
+	timeToFirstFrame.Observe(time.Since(start).Seconds())
+}
+
+// instrumentedTableCacheProvider wraps a TableCacheProvider with latency
+// histograms and error counters, so any provider implementation gets the
+// same observability for free.
+type instrumentedTableCacheProvider struct {
+	next TableCacheProvider
+}
+
+func newInstrumentedTableCacheProvider(next TableCacheProvider) *instrumentedTableCacheProvider {
+	return &instrumentedTableCacheProvider{next: next}
+}
+
+func (p *instrumentedTableCacheProvider) Lookup(ctx context.Context, name string) ([]string, error) {
+	start := time.Now()
+	caches, err := p.next.Lookup(ctx, name)
+	providerLookupDuration.WithLabelValues("lookup").Observe(time.Since(start).Seconds())
+	if err != nil && err != ErrTableNotFound {
+		providerErrorsTotal.WithLabelValues("lookup").Inc()
+	}
+	return caches, err
+}
+
+func (p *instrumentedTableCacheProvider) List(ctx context.Context) ([]string, error) {
+	start := time.Now()
+	names, err := p.next.List(ctx)
+	providerLookupDuration.WithLabelValues("list").Observe(time.Since(start).Seconds())
+	if err != nil {
+		providerErrorsTotal.WithLabelValues("list").Inc()
+	}
+	return names, err
+}
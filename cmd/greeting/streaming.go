@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultChunkWords is how many words go into each SSE frame when
+// SSE_CHUNK_SIZE isn't set.
+const defaultChunkWords = 4
+
+// defaultStreamDeadline bounds how long a single chat response may take to
+// stream before we give up on the client.
+const defaultStreamDeadline = 30 * time.Second
+
+// deadlineTimer closes done after the given duration, analogous to the
+// netstack deadlineTimer: a cancel channel a select loop can wait on
+// alongside other signals, armed with time.AfterFunc so stop() is cheap
+// when the stream finishes before the deadline.
+type deadlineTimer struct {
+	done  chan struct{}
+	timer *time.Timer
+}
+
+func newDeadlineTimer(d time.Duration) *deadlineTimer {
+	done := make(chan struct{})
+	timer := time.AfterFunc(d, func() { close(done) })
+	return &deadlineTimer{done: done, timer: timer}
+}
+
+func (t *deadlineTimer) stop() {
+	t.timer.Stop()
+}
+
+func streamChunkWords() int {
+	if v := os.Getenv("SSE_CHUNK_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultChunkWords
+}
+
+func streamDeadline() time.Duration {
+	if v := os.Getenv("SSE_STREAM_DEADLINE_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return defaultStreamDeadline
+}
+
+// tokenPattern splits content into alternating runs of non-whitespace and
+// whitespace, so re-concatenating the tokens reproduces the input exactly
+// (newlines, repeated spaces, bullets, and all).
+var tokenPattern = regexp.MustCompile(`\S+|\s+`)
+
+// chunkContent splits content into groups of chunkWords words, carrying
+// along whatever whitespace originally separated them so concatenating
+// the chunks reproduces the original text byte-for-byte.
+func chunkContent(content string, chunkWords int) []string {
+	tokens := tokenPattern.FindAllString(content, -1)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	var chunks []string
+	var b strings.Builder
+	words := 0
+
+	flush := func() {
+		if b.Len() > 0 {
+			chunks = append(chunks, b.String())
+			b.Reset()
+		}
+	}
+
+	for _, tok := range tokens {
+		b.WriteString(tok)
+		if strings.TrimSpace(tok) != "" {
+			words++
+			if words == chunkWords {
+				flush()
+				words = 0
+			}
+		}
+	}
+	flush()
+
+	return chunks
+}
+
+// streamChunk is one unit of a chunked response, produced by produceChunks
+// and consumed by writeSSEStream.
+type streamChunk struct {
+	content string
+	final   bool
+}
+
+// produceChunks feeds chunks onto out, one at a time, stopping early if
+// cancel or deadline fires before the consumer reads the next one. Always
+// closes out so the consumer can tell when production is done.
+func produceChunks(out chan<- streamChunk, chunks []string, cancel, deadline <-chan struct{}) {
+	defer close(out)
+	for i, chunk := range chunks {
+		select {
+		case out <- streamChunk{content: chunk, final: i == len(chunks)-1}:
+		case <-cancel:
+			return
+		case <-deadline:
+			return
+		}
+	}
+}
+
+// writeSSEStream writes content to w as a sequence of `data:` frames, one
+// per chunk, flushing after each so Copilot renders the response as it
+// arrives. Chunks are produced on a channel by produceChunks so the
+// writer loop can select between the next chunk, the client disconnecting
+// (r.Context().Done()), and a configurable per-request deadline — a
+// cancel channel closed by time.AfterFunc, the same pattern as the
+// netstack deadlineTimer. Always terminates with a final finish_reason
+// frame and a `data: [DONE]` sentinel.
+func writeSSEStream(w http.ResponseWriter, r *http.Request, content string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeSSEFrame(w, "assistant", content, true)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		return
+	}
+
+	chunks := chunkContent(content, streamChunkWords())
+	if len(chunks) == 0 {
+		chunks = []string{""}
+	}
+
+	deadline := newDeadlineTimer(streamDeadline())
+	defer deadline.stop()
+
+	chunkCh := make(chan streamChunk)
+	go produceChunks(chunkCh, chunks, r.Context().Done(), deadline.done)
+
+	first := true
+	for {
+		select {
+		case chunk, open := <-chunkCh:
+			if !open {
+				fmt.Fprint(w, "data: [DONE]\n\n")
+				flusher.Flush()
+				return
+			}
+
+			role := ""
+			if first {
+				role = "assistant"
+			}
+			writeSSEFrame(w, role, chunk.content, chunk.final)
+			flusher.Flush()
+			if first {
+				recordTimeToFirstFrame(r.Context())
+				first = false
+			}
+		case <-r.Context().Done():
+			logger.Warn("stream aborted: client disconnected", "request_id", requestIDFromContext(r.Context()))
+			return
+		case <-deadline.done:
+			logger.Warn("stream aborted: deadline exceeded", "request_id", requestIDFromContext(r.Context()), "deadline", streamDeadline().String())
+			return
+		}
+	}
+}
+
+// writeSSEFrame marshals a single Copilot choice delta and writes it as one
+// `data:` frame. The initial assistant turn also advertises the
+// get_table_cache tool, so Copilot can choose to call it on a later turn
+// instead of relying on our free-text heuristics.
+func writeSSEFrame(w http.ResponseWriter, role, content string, final bool) {
+	choice := Choice{
+		Index: 0,
+		Delta: Delta{
+			Role:    role,
+			Content: content,
+		},
+	}
+	if final {
+		choice.Finish = "stop"
+	}
+
+	response := CopilotResponse{Choices: []Choice{choice}}
+	if role == "assistant" {
+		response.Tools = []Tool{getTableCacheTool()}
+	}
+	jsonData, _ := json.Marshal(response)
+	fmt.Fprintf(w, "data: %s\n\n", jsonData)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteSSEStream_EndToEnd(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	rec := httptest.NewRecorder()
+
+	writeSSEStream(rec, req, "one two three four five six")
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `"finish_reason":"stop"`) {
+		t.Fatalf("expected a finish_reason frame, got %q", body)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(body), "data: [DONE]") {
+		t.Fatalf("expected stream to end with a [DONE] sentinel, got %q", body)
+	}
+}
+
+func TestChunkContent_PreservesWhitespace(t *testing.T) {
+	content := "Hello! 👋 I'm your Table Cache Extension!\n\n" +
+		"Here's how to use me:\n\n" +
+		"**Available Commands:**\n" +
+		"• Just say \"hi\" or \"hello\" for this greeting\n" +
+		"• Ask \"what's the table cache of [TABLE_NAME]\" to get cache details"
+
+	chunks := chunkContent(content, 4)
+
+	var rebuilt strings.Builder
+	for _, c := range chunks {
+		rebuilt.WriteString(c)
+	}
+	if rebuilt.String() != content {
+		t.Fatalf("chunks did not reconstruct the original content byte-for-byte:\ngot:  %q\nwant: %q", rebuilt.String(), content)
+	}
+}
+
+func TestWriteSSEStream_PreservesMultilineContent(t *testing.T) {
+	content := getGreetingMessage()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	rec := httptest.NewRecorder()
+	writeSSEStream(rec, req, content)
+
+	frames := decodeSSEFrames(t, rec.Body.String())
+	var rebuilt strings.Builder
+	for _, f := range frames {
+		rebuilt.WriteString(f.Choices[0].Delta.Content)
+	}
+	if rebuilt.String() != content {
+		t.Fatalf("streamed frames did not reconstruct the greeting byte-for-byte:\ngot:  %q\nwant: %q", rebuilt.String(), content)
+	}
+}
+
+func TestWriteSSEStream_StopsOnClientDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	writeSSEStream(rec, req, "one two three four five six")
+
+	if strings.Contains(rec.Body.String(), "[DONE]") {
+		t.Fatal("expected an already-cancelled request not to reach the [DONE] sentinel")
+	}
+}
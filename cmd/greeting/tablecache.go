@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// TableCacheProvider looks up which caches back a given table. It exists so
+// the backing store can be swapped (in-memory, SQL, a remote service)
+// without touching the handlers that use it.
+type TableCacheProvider interface {
+	Lookup(ctx context.Context, name string) ([]string, error)
+	List(ctx context.Context) ([]string, error)
+}
+
+// ErrTableNotFound is returned by a provider when the requested table has
+// no known caches.
+var ErrTableNotFound = fmt.Errorf("table not found in cache system")
+
+// memoryTableCacheProvider serves the original hardcoded table list. It's
+// the default provider and what local dev runs against.
+type memoryTableCacheProvider struct {
+	tables map[string][]string
+}
+
+func newMemoryTableCacheProvider() *memoryTableCacheProvider {
+	return &memoryTableCacheProvider{
+		tables: map[string][]string{
+			"TBCD":   {"MENUCACHE", "APICACHE", "TRANSCACHE"},
+			"USERS":  {"USERCACHE", "REGISTRYCACHE"},
+			"ORDERS": {"ORDERCACHE"},
+		},
+	}
+}
+
+func (p *memoryTableCacheProvider) Lookup(ctx context.Context, name string) ([]string, error) {
+	caches, ok := p.tables[name]
+	if !ok {
+		return nil, ErrTableNotFound
+	}
+	return caches, nil
+}
+
+func (p *memoryTableCacheProvider) List(ctx context.Context) ([]string, error) {
+	names := make([]string, 0, len(p.tables))
+	for name := range p.tables {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// sqlTableCacheProvider looks up caches from a `table_caches` table with
+// columns (table_name, cache_name). The driver must already be registered
+// via a blank import (e.g. `_ "github.com/lib/pq"`) for CACHE_DRIVER to
+// resolve.
+type sqlTableCacheProvider struct {
+	db     *sql.DB
+	driver string
+}
+
+func newSQLTableCacheProvider(driver, dsn string) (*sqlTableCacheProvider, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open sql cache provider: %w", err)
+	}
+	return &sqlTableCacheProvider{db: db, driver: driver}, nil
+}
+
+// placeholderFor returns the positional bind-parameter syntax for the
+// given driver, since it isn't standardized across database/sql drivers:
+// Postgres wants $1, SQL Server wants @p1, and everything else (MySQL,
+// SQLite, ...) wants a plain ?.
+func placeholderFor(driver string, position int) string {
+	switch driver {
+	case "postgres", "pgx", "cloudsqlpostgres":
+		return fmt.Sprintf("$%d", position)
+	case "sqlserver", "mssql":
+		return fmt.Sprintf("@p%d", position)
+	default:
+		return "?"
+	}
+}
+
+func (p *sqlTableCacheProvider) Lookup(ctx context.Context, name string) ([]string, error) {
+	query := fmt.Sprintf(`SELECT cache_name FROM table_caches WHERE table_name = %s`, placeholderFor(p.driver, 1))
+	rows, err := p.db.QueryContext(ctx, query, name)
+	if err != nil {
+		return nil, fmt.Errorf("query table caches: %w", err)
+	}
+	defer rows.Close()
+
+	var caches []string
+	for rows.Next() {
+		var cache string
+		if err := rows.Scan(&cache); err != nil {
+			return nil, fmt.Errorf("scan cache row: %w", err)
+		}
+		caches = append(caches, cache)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(caches) == 0 {
+		return nil, ErrTableNotFound
+	}
+	return caches, nil
+}
+
+func (p *sqlTableCacheProvider) List(ctx context.Context) ([]string, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT DISTINCT table_name FROM table_caches`)
+	if err != nil {
+		return nil, fmt.Errorf("query table names: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan table name row: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// httpTableCacheProvider fetches the cache listing from a remote JSON
+// endpoint, e.g. `GET {baseURL}?table=TBCD` returning {"caches": [...]}.
+type httpTableCacheProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+func newHTTPTableCacheProvider(baseURL string) *httpTableCacheProvider {
+	return &httpTableCacheProvider{
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// urlWithQuery adds a query parameter to baseURL, merging with (rather
+// than clobbering) any query string baseURL already has, and percent-
+// encoding the value.
+func (p *httpTableCacheProvider) urlWithQuery(key, value string) (string, error) {
+	u, err := url.Parse(p.baseURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func (p *httpTableCacheProvider) Lookup(ctx context.Context, name string) ([]string, error) {
+	reqURL, err := p.urlWithQuery("table", name)
+	if err != nil {
+		return nil, fmt.Errorf("build cache provider url: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch table caches: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrTableNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cache provider returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Caches []string `json:"caches"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode cache provider response: %w", err)
+	}
+	return body.Caches, nil
+}
+
+func (p *httpTableCacheProvider) List(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch table names: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cache provider returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Tables []string `json:"tables"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return body.Tables, nil
+}
+
+// newTableCacheProviderFromEnv selects a provider based on CACHE_PROVIDER
+// (memory, sql, http), defaulting to the in-memory provider.
+func newTableCacheProviderFromEnv() (TableCacheProvider, error) {
+	switch strings.ToLower(os.Getenv("CACHE_PROVIDER")) {
+	case "", "memory":
+		return newMemoryTableCacheProvider(), nil
+	case "sql":
+		driver := os.Getenv("CACHE_DRIVER")
+		dsn := os.Getenv("CACHE_DSN")
+		if driver == "" || dsn == "" {
+			return nil, fmt.Errorf("CACHE_DRIVER and CACHE_DSN are required for CACHE_PROVIDER=sql")
+		}
+		return newSQLTableCacheProvider(driver, dsn)
+	case "http":
+		httpURL := os.Getenv("CACHE_HTTP_URL")
+		if httpURL == "" {
+			return nil, fmt.Errorf("CACHE_HTTP_URL is required for CACHE_PROVIDER=http")
+		}
+		return newHTTPTableCacheProvider(httpURL), nil
+	default:
+		return nil, fmt.Errorf("unknown CACHE_PROVIDER %q", os.Getenv("CACHE_PROVIDER"))
+	}
+}
+
+// formatTableCacheResponse renders the provider's lookup result (or error)
+// as the chat message text.
+func formatTableCacheResponse(tableName string, caches []string, err error) string {
+	if err != nil {
+		if err == ErrTableNotFound {
+			return fmt.Sprintf(`Table Cache Information for %s:
+- Status: Table not found in cache system
+- Suggestion: Please check if the table name is correct
+- Contact admin if you need to add this table to cache monitoring`, tableName)
+		}
+		return fmt.Sprintf("Sorry, I couldn't look up the table cache for %s right now. Please try again shortly.", tableName)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Table Cache Information for %s:\n", tableName)
+	for _, cache := range caches {
+		fmt.Fprintf(&b, "- %s\n", cache)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestPlaceholderFor(t *testing.T) {
+	cases := []struct {
+		driver string
+		want   string
+	}{
+		{"postgres", "$1"},
+		{"pgx", "$1"},
+		{"sqlserver", "@p1"},
+		{"mysql", "?"},
+		{"sqlite3", "?"},
+	}
+	for _, c := range cases {
+		if got := placeholderFor(c.driver, 1); got != c.want {
+			t.Errorf("placeholderFor(%q, 1) = %q, want %q", c.driver, got, c.want)
+		}
+	}
+}
+
+func TestHTTPTableCacheProvider_URLWithQuery(t *testing.T) {
+	p := newHTTPTableCacheProvider("https://cache.example.com/lookup?env=prod")
+
+	got, err := p.urlWithQuery("table", "TBCD?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "https://cache.example.com/lookup?env=prod&table=TBCD%3F"
+	if got != want {
+		t.Fatalf("urlWithQuery() = %q, want %q", got, want)
+	}
+}
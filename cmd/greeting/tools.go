@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// getTableCacheToolName is the function name advertised to, and invoked
+// by, Copilot for table cache lookups.
+const getTableCacheToolName = "get_table_cache"
+
+// Tool describes a function Copilot may call, following the OpenAI-style
+// tools schema Copilot's chat completions API expects.
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolCall is an invocation of a Tool requested by the assistant turn.
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// getTableCacheTool is advertised on every chat turn so Copilot can choose
+// to call it instead of relying on our free-text heuristic parser.
+func getTableCacheTool() Tool {
+	return Tool{
+		Type: "function",
+		Function: ToolFunction{
+			Name:        getTableCacheToolName,
+			Description: "Look up the caches backing a given database table.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"table_name": {
+						"type": "string",
+						"description": "The table to look up, e.g. TBCD"
+					}
+				},
+				"required": ["table_name"]
+			}`),
+		},
+	}
+}
+
+// clientSupportsTools reports whether the incoming request advertised any
+// tools, i.e. whether Copilot is willing to drive a function-calling turn
+// instead of just reading our free-text reply.
+func clientSupportsTools(req CopilotRequest) bool {
+	return len(req.Tools) > 0
+}
+
+var toolCallCounter uint64
+
+func nextToolCallID() string {
+	id := atomic.AddUint64(&toolCallCounter, 1)
+	return fmt.Sprintf("call_%d", id)
+}
+
+// tableCacheToolResult is the JSON payload returned to Copilot as the
+// `tool` role message once get_table_cache has been dispatched.
+type tableCacheToolResult struct {
+	TableName string   `json:"table_name"`
+	Caches    []string `json:"caches,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// writeToolCallStream advertises the get_table_cache call, dispatches it
+// against the provider, and streams back the tool_calls delta, the tool
+// result message, and a final human-readable assistant summary.
+func writeToolCallStream(w http.ResponseWriter, r *http.Request, provider TableCacheProvider, tableName string) {
+	flusher, _ := w.(http.Flusher)
+
+	toolCallID := nextToolCallID()
+	arguments, _ := json.Marshal(map[string]string{"table_name": tableName})
+
+	writeToolCallFrame(w, toolCallID, getTableCacheToolName, string(arguments))
+	if flusher != nil {
+		flusher.Flush()
+		recordTimeToFirstFrame(r.Context())
+	}
+
+	caches, err := provider.Lookup(r.Context(), tableName)
+	if err != nil {
+		logger.Warn("tool call failed",
+			"request_id", requestIDFromContext(r.Context()),
+			"tool", getTableCacheToolName,
+			"table_name", tableName,
+			"error", err.Error(),
+		)
+	}
+
+	result := tableCacheToolResult{TableName: tableName, Caches: caches}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	resultJSON, _ := json.Marshal(result)
+
+	writeToolResultFrame(w, toolCallID, string(resultJSON))
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	writeSSEStream(w, r, formatTableCacheResponse(tableName, caches, err))
+}
+
+func writeToolCallFrame(w http.ResponseWriter, toolCallID, name, arguments string) {
+	response := CopilotResponse{
+		Choices: []Choice{
+			{
+				Index: 0,
+				Delta: Delta{
+					Role: "assistant",
+					ToolCalls: []ToolCall{
+						{
+							ID:   toolCallID,
+							Type: "function",
+							Function: ToolCallFunction{
+								Name:      name,
+								Arguments: arguments,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	jsonData, _ := json.Marshal(response)
+	fmt.Fprintf(w, "data: %s\n\n", jsonData)
+}
+
+func writeToolResultFrame(w http.ResponseWriter, toolCallID, content string) {
+	response := CopilotResponse{
+		Choices: []Choice{
+			{
+				Index: 0,
+				Delta: Delta{
+					Role:       "tool",
+					Content:    content,
+					ToolCallID: toolCallID,
+				},
+			},
+		},
+	}
+	jsonData, _ := json.Marshal(response)
+	fmt.Fprintf(w, "data: %s\n\n", jsonData)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func decodeSSEFrames(t *testing.T, body string) []CopilotResponse {
+	t.Helper()
+
+	var frames []CopilotResponse
+	for _, line := range strings.Split(body, "\n") {
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		payload := strings.TrimPrefix(line, "data: ")
+		if payload == "[DONE]" {
+			continue
+		}
+		var frame CopilotResponse
+		if err := json.Unmarshal([]byte(payload), &frame); err != nil {
+			t.Fatalf("failed to decode SSE frame %q: %v", payload, err)
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func chatRequest(t *testing.T, body CopilotRequest) *httptest.ResponseRecorder {
+	t.Helper()
+
+	raw, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(raw))
+	rec := httptest.NewRecorder()
+	chatHandler(rec, req)
+	return rec
+}
+
+func TestChatHandler_ToolCallPath(t *testing.T) {
+	tableCacheProvider = newMemoryTableCacheProvider()
+
+	rec := chatRequest(t, CopilotRequest{
+		Messages: []Message{{Role: "user", Content: "what's the table cache of TBCD"}},
+		Tools:    []Tool{getTableCacheTool()},
+	})
+
+	frames := decodeSSEFrames(t, rec.Body.String())
+	if len(frames) < 2 {
+		t.Fatalf("expected at least a tool_calls frame and a tool result frame, got %d frames", len(frames))
+	}
+
+	toolCallFrame := frames[0].Choices[0]
+	if len(toolCallFrame.Delta.ToolCalls) != 1 {
+		t.Fatalf("expected first frame to carry one tool call, got %d", len(toolCallFrame.Delta.ToolCalls))
+	}
+	if toolCallFrame.Delta.ToolCalls[0].Function.Name != getTableCacheToolName {
+		t.Fatalf("expected tool call for %q, got %q", getTableCacheToolName, toolCallFrame.Delta.ToolCalls[0].Function.Name)
+	}
+
+	toolResultFrame := frames[1].Choices[0]
+	if toolResultFrame.Delta.Role != "tool" {
+		t.Fatalf("expected second frame to have role %q, got %q", "tool", toolResultFrame.Delta.Role)
+	}
+	var result tableCacheToolResult
+	if err := json.Unmarshal([]byte(toolResultFrame.Delta.Content), &result); err != nil {
+		t.Fatalf("failed to decode tool result: %v", err)
+	}
+	if result.TableName != "TBCD" || len(result.Caches) == 0 {
+		t.Fatalf("unexpected tool result: %+v", result)
+	}
+}
+
+func TestChatHandler_HeuristicFallbackPath(t *testing.T) {
+	tableCacheProvider = newMemoryTableCacheProvider()
+
+	rec := chatRequest(t, CopilotRequest{
+		Messages: []Message{{Role: "user", Content: "what's the table cache of TBCD"}},
+	})
+
+	frames := decodeSSEFrames(t, rec.Body.String())
+	if len(frames) == 0 {
+		t.Fatal("expected at least one SSE frame")
+	}
+	for _, frame := range frames {
+		if len(frame.Choices[0].Delta.ToolCalls) != 0 {
+			t.Fatal("did not expect tool_calls when the client didn't advertise tools")
+		}
+	}
+
+	var combined strings.Builder
+	for _, frame := range frames {
+		combined.WriteString(frame.Choices[0].Delta.Content)
+	}
+	if !strings.Contains(combined.String(), "MENUCACHE") {
+		t.Fatalf("expected fallback text response to mention MENUCACHE, got %q", combined.String())
+	}
+}
+